@@ -0,0 +1,212 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import (
+	"strconv"
+)
+
+// maxArrayLength is 2^32 - 1, the largest length an Array may have,
+// per ES5.1 §15.4.
+const maxArrayLength = 1<<32 - 1
+
+// Array is an exotic object implementing JavaScript's Array, whose
+// distinguishing feature is a magic "length" property: per §15.4.5.1,
+// writing to an integer-indexed property beyond the current length
+// grows length to match, and writing to length directly truncates
+// (deleting higher-indexed elements) or simply records a larger
+// length.
+type Array struct {
+	object
+}
+
+// *Array must satisfy Object.
+var _ Object = (*Array)(nil)
+
+// NewArray creates a new, empty Array with the given owner and
+// prototype (usually Array.prototype).
+func NewArray(owner *Owner, proto Object) *Array {
+	arr := new(Array)
+	arr.init(owner, proto)
+	arr.Methods = &ArrayInternalMethods
+	arr.self = arr
+	arr.properties["length"] = Property{
+		Value: Number(0),
+		Owner: owner,
+		W:     true,
+		E:     false,
+		C:     false,
+		R:     true,
+	}
+	return arr
+}
+
+// ArrayInternalMethods is the InternalMethods table used by Array
+// objects; it differs from OrdinaryInternalMethods only in how it
+// handles [[Set]] and [[DefineOwnProperty]] of "length" and of
+// array-index properties.
+var ArrayInternalMethods = InternalMethods{
+	GetPrototypeOf:    ordinaryGetPrototypeOf,
+	SetPrototypeOf:    ordinarySetPrototypeOf,
+	IsExtensible:      ordinaryIsExtensible,
+	PreventExtensions: ordinaryPreventExtensions,
+	Get:               ordinaryGet,
+	Set:               arraySet,
+	Delete:            ordinaryDelete,
+	HasProperty:       ordinaryHasProperty,
+	OwnPropertyKeys:   ordinaryOwnPropertyKeys,
+	DefineOwnProperty: arrayDefineOwnProperty,
+}
+
+// arrayIndex returns the array index denoted by key, and true, if key
+// is a canonical-form non-negative integer string less than
+// maxArrayLength; otherwise it returns (0, false).
+func arrayIndex(key string) (uint32, bool) {
+	if key == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(key, 10, 32)
+	if err != nil || n >= maxArrayLength || strconv.FormatUint(n, 10) != key {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+// arrayLength returns the current value of obj's "length" property.
+func arrayLength(obj *object) uint32 {
+	return uint32(obj.properties["length"].Value.(Number))
+}
+
+// setArrayLength updates obj's "length" property to newLen, without
+// performing any of the truncation or validity checks associated with
+// an explicit length-set; callers are expected to already have
+// established that newLen is an acceptable value.
+func setArrayLength(obj *object, newLen uint32) {
+	length := obj.properties["length"]
+	length.Value = Number(newLen)
+	obj.properties["length"] = length
+}
+
+// arraySet implements Array's [[Set]] internal method, per the
+// relevant parts of ES5.1 §15.4.5.1.
+func arraySet(obj *object, caller *Owner, key string, value Value) *NativeError {
+	if key == "length" {
+		newLen, err := toArrayLength(value)
+		if err != nil {
+			return err
+		}
+		if err := checkWrite(obj.properties["length"], caller); err != nil {
+			return err
+		}
+		return setLengthAndTruncate(obj, caller, newLen, true)
+	}
+	if idx, ok := arrayIndex(key); ok {
+		oldLen := arrayLength(obj)
+		if idx >= oldLen && !obj.properties["length"].W {
+			return nil // silently ignored: length is not writable
+		}
+		if err := ordinarySet(obj, caller, key, value); err != nil {
+			return err
+		}
+		if idx >= oldLen {
+			setArrayLength(obj, idx+1)
+		}
+		return nil
+	}
+	return ordinarySet(obj, caller, key, value)
+}
+
+// arrayDefineOwnProperty implements Array's [[DefineOwnProperty]]
+// internal method, per ES5.1 §15.4.5.1.
+func arrayDefineOwnProperty(obj *object, caller *Owner, key string, desc PropertyDescriptor, throw bool) *NativeError {
+	if key == "length" {
+		if !desc.HasValue {
+			return ordinaryDefineOwnProperty(obj, caller, key, desc, throw)
+		}
+		newLen, err := toArrayLength(desc.Value)
+		if err != nil {
+			return err
+		}
+		oldLen := arrayLength(obj)
+		desc.Value = Number(newLen)
+		if err := ordinaryDefineOwnProperty(obj, caller, key, desc, throw); err != nil {
+			return err
+		}
+		if arrayLength(obj) != newLen {
+			return nil // silently rejected by ordinaryDefineOwnProperty
+		}
+		setArrayLength(obj, oldLen)
+		return setLengthAndTruncate(obj, caller, newLen, throw)
+	}
+	if idx, ok := arrayIndex(key); ok {
+		oldLen := arrayLength(obj)
+		if idx >= oldLen && !obj.properties["length"].W {
+			return rejectOrThrow(throw, "cannot add index beyond non-writable length")
+		}
+		if err := ordinaryDefineOwnProperty(obj, caller, key, desc, throw); err != nil {
+			return err
+		}
+		if idx >= oldLen {
+			setArrayLength(obj, idx+1)
+		}
+		return nil
+	}
+	return ordinaryDefineOwnProperty(obj, caller, key, desc, throw)
+}
+
+// toArrayLength converts value to a valid array length (a uint32),
+// or returns a RangeError if it is not representable as one, per
+// ES5.1 §15.4.5.1 step 3.c.
+func toArrayLength(value Value) (uint32, *NativeError) {
+	n := value.ToNumber()
+	newLen := uint32(n)
+	if Number(newLen) != n {
+		return 0, NewNativeError(RangeError, "invalid array length")
+	}
+	return newLen, nil
+}
+
+// setLengthAndTruncate sets obj's length property to newLen, deleting
+// any now out-of-range index properties (subject to the same
+// ownership/configurability checks as an ordinary Delete); if one of
+// those properties turns out to be non-deletable, length is instead
+// set to one more than that property's index and a reject/throw
+// results, per ES5.1 §15.4.5.1 step 3.l-n.
+func setLengthAndTruncate(obj *object, caller *Owner, newLen uint32, throw bool) *NativeError {
+	oldLen := arrayLength(obj)
+	if newLen >= oldLen {
+		setArrayLength(obj, newLen)
+		return nil
+	}
+	for i := oldLen; i > newLen; i-- {
+		key := strconv.FormatUint(uint64(i-1), 10)
+		pd, ok := obj.properties[key]
+		if !ok {
+			continue
+		}
+		if err := checkDelete(pd, caller); err != nil {
+			setArrayLength(obj, i)
+			if !throw {
+				return nil
+			}
+			return err
+		}
+		delete(obj.properties, key)
+	}
+	setArrayLength(obj, newLen)
+	return nil
+}
@@ -0,0 +1,40 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+// testFunc is a minimal stand-in for the interpreter's function-value
+// type: an ordinary object (so it satisfies Value/Object, just as a
+// real function does) whose Call method runs an arbitrary Go func.
+// It lets tests install getters/setters/traps without depending on
+// the interpreter package.
+type testFunc struct {
+	object
+	fn func(this Value, args []Value) (Value, *NativeError)
+}
+
+func newTestFunc(owner *Owner, fn func(this Value, args []Value) (Value, *NativeError)) *testFunc {
+	f := new(testFunc)
+	f.init(owner, nil)
+	f.Methods = &OrdinaryInternalMethods
+	f.self = f
+	f.fn = fn
+	return f
+}
+
+func (f *testFunc) Call(this Value, args []Value) (Value, *NativeError) {
+	return f.fn(this, args)
+}
@@ -0,0 +1,191 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+// LazyObject is an exotic object that defers the construction of some
+// of its own properties until they are first accessed—borrowing the
+// "LazyLoader" pattern from Piscēs.  This is intended for builtin
+// objects like Object.prototype and Array.prototype, whose properties
+// are numerous, expensive to build, and routinely reference each
+// other in ways that would otherwise force a careful (and brittle)
+// initialization order.
+//
+// Each pending property is backed by a LazyInit rather than an
+// ordinary Property; the first Get of that key runs the initializer
+// and replaces the entry with an ordinary property using the flags
+// declared in the LazyInit, after which the object behaves exactly
+// like a plain object for that key.  A Set that arrives before the
+// first Get simply cancels the pending initializer (as if it had
+// already run and the result had then been overwritten) rather than
+// running it.  A Delete that arrives before the first Get instead
+// runs the initializer and then deletes the resulting property,
+// subject to the usual checkDelete checks (see lazyDelete).
+type LazyObject struct {
+	object
+	inits map[string]LazyInit
+}
+
+// *LazyObject must satisfy Object.
+var _ Object = (*LazyObject)(nil)
+
+// LazyInit bundles a property initializer with the descriptor flags
+// to be applied to the property it creates, per §8.10 of ES5.1.
+type LazyInit struct {
+	// Init is called, at most once, the first time the property is
+	// read or deleted; its return value becomes the property's Value.
+	Init    func(*LazyObject) (Value, *NativeError)
+	W, E, C bool
+	R       bool
+}
+
+// NewLazyObject creates a new LazyObject with the given owner and
+// prototype, with one pending property for each entry of inits.
+func NewLazyObject(owner *Owner, proto Object, inits map[string]LazyInit) *LazyObject {
+	lo := new(LazyObject)
+	lo.init(owner, proto)
+	lo.Methods = &LazyInternalMethods
+	lo.self = lo
+	lo.inits = make(map[string]LazyInit, len(inits))
+	for key, li := range inits {
+		lo.inits[key] = li
+	}
+	return lo
+}
+
+// LazyInternalMethods is the InternalMethods table used by
+// LazyObject; it differs from OrdinaryInternalMethods only in that
+// [[Get]], [[Set]], [[Delete]], [[HasProperty]] and
+// [[OwnPropertyKeys]] also take pending (not yet realised)
+// initializers into account.
+var LazyInternalMethods = InternalMethods{
+	GetPrototypeOf:    ordinaryGetPrototypeOf,
+	SetPrototypeOf:    ordinarySetPrototypeOf,
+	IsExtensible:      ordinaryIsExtensible,
+	PreventExtensions: ordinaryPreventExtensions,
+	Get:               lazyGet,
+	Set:               lazySet,
+	Delete:            lazyDelete,
+	HasProperty:       lazyHasProperty,
+	OwnPropertyKeys:   lazyOwnPropertyKeys,
+	DefineOwnProperty: ordinaryDefineOwnProperty,
+}
+
+// realize runs the pending initializer for key (if there is one),
+// installs its result as an ordinary property using the flags
+// declared alongside it, and removes the initializer so that it is
+// never run again.  It is a no-op if key has no pending initializer.
+func (lo *LazyObject) realize(key string) *NativeError {
+	li, ok := lo.inits[key]
+	if !ok {
+		return nil
+	}
+	delete(lo.inits, key)
+	value, err := li.Init(lo)
+	if err != nil {
+		return err
+	}
+	lo.properties[key] = Property{
+		Value: value,
+		Owner: lo.owner,
+		W:     li.W,
+		E:     li.E,
+		C:     li.C,
+		R:     li.R,
+	}
+	return nil
+}
+
+func lazyGet(obj *object, caller *Owner, key string) (Value, *NativeError) {
+	lo := obj.self.(*LazyObject)
+	if err := lo.realize(key); err != nil {
+		return Undefined{}, err
+	}
+	return ordinaryGet(obj, caller, key)
+}
+
+// lazySet implements [[Set]] for LazyObject: a write to a key with a
+// pending initializer cancels that initializer—without running it—
+// and then proceeds as an ordinary [[Set]], which will create a new,
+// ordinarily-flagged own property since none exists yet.
+func lazySet(obj *object, caller *Owner, key string, value Value) *NativeError {
+	lo := obj.self.(*LazyObject)
+	delete(lo.inits, key)
+	return ordinarySet(obj, caller, key, value)
+}
+
+// lazyDelete deletes key, realising its pending initializer first (if
+// it has one) so that the delete is subject to the same checkDelete
+// check—against the LazyInit's declared C flag and owner—as deleting
+// any other property; without this, a pending initializer could be
+// cancelled by any caller regardless of its declared permissions,
+// since there would be no Property yet for checkDelete to consult.
+func lazyDelete(obj *object, caller *Owner, key string) *NativeError {
+	lo := obj.self.(*LazyObject)
+	if _, pending := lo.inits[key]; pending {
+		if err := lo.realize(key); err != nil {
+			return err
+		}
+	}
+	return ordinaryDelete(obj, caller, key)
+}
+
+// lazyReadable reports whether caller is entitled to know about a
+// pending (not yet realised) initializer, using the R flag and owner
+// it will be given once realised—the same check that would apply to
+// the equivalent real Property, and without running the initializer
+// to find out.
+func lazyReadable(lo *LazyObject, li LazyInit, caller *Owner) bool {
+	return checkRead(Property{Owner: lo.owner, R: li.R}, caller) == nil
+}
+
+// HasOwnProperty overrides (object).HasOwnProperty so that a pending
+// initializer is reported as present only if caller would be allowed
+// to read the property it will become; this mirrors lazyHasProperty,
+// which handles the [[HasProperty]] case.
+func (lo *LazyObject) HasOwnProperty(caller *Owner, key string) bool {
+	if li, ok := lo.inits[key]; ok {
+		return lazyReadable(lo, li, caller)
+	}
+	return lo.object.HasOwnProperty(caller, key)
+}
+
+func lazyHasProperty(obj *object, caller *Owner, key string) bool {
+	lo := obj.self.(*LazyObject)
+	if li, ok := lo.inits[key]; ok {
+		return lazyReadable(lo, li, caller)
+	}
+	return ordinaryHasProperty(obj, caller, key)
+}
+
+// lazyOwnPropertyKeys includes keys with a pending initializer
+// alongside already-realised own properties, omitting any pending key
+// caller would not be permitted to read once realised (matching
+// ordinaryOwnPropertyKeys, which applies the same checkRead filter to
+// real properties), and without triggering realisation of any of
+// them.
+func lazyOwnPropertyKeys(obj *object, caller *Owner) []string {
+	lo := obj.self.(*LazyObject)
+	keys := make([]string, 0, len(lo.inits)+len(obj.properties))
+	for key, li := range lo.inits {
+		if !lazyReadable(lo, li, caller) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	keys = append(keys, ordinaryOwnPropertyKeys(obj, caller)...)
+	return keys
+}
@@ -0,0 +1,130 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import "testing"
+
+func TestLazyObjectInitRunsOnce(t *testing.T) {
+	owner := &Owner{}
+	calls := 0
+	lo := NewLazyObject(owner, nil, map[string]LazyInit{
+		"x": {
+			Init: func(*LazyObject) (Value, *NativeError) {
+				calls++
+				return Number(42), nil
+			},
+			W: true, E: true, C: true, R: true,
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		v, err := lo.Get(owner, "x")
+		if err != nil || v != Number(42) {
+			t.Fatalf("Get(\"x\") = %v, %v; want 42, nil", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Init called %d times, want 1", calls)
+	}
+}
+
+func TestLazyObjectWriteBeforeReadCancelsInit(t *testing.T) {
+	owner := &Owner{}
+	called := false
+	lo := NewLazyObject(owner, nil, map[string]LazyInit{
+		"x": {
+			Init: func(*LazyObject) (Value, *NativeError) {
+				called = true
+				return Number(1), nil
+			},
+			W: true, E: true, C: true, R: true,
+		},
+	})
+	if err := lo.Set(owner, "x", Number(2)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	v, err := lo.Get(owner, "x")
+	if err != nil || v != Number(2) {
+		t.Errorf("Get(\"x\") = %v, %v; want 2, nil", v, err)
+	}
+	if called {
+		t.Errorf("Init should not have run after a pre-emptive write")
+	}
+}
+
+// TestLazyObjectOwnPropertyKeysHidesUnreadableInit ensures a pending
+// LazyInit declared R: false is not leaked via OwnPropertyKeys (or
+// HasOwnProperty/HasProperty) to a caller who is not its owner, just
+// as an already-realised non-world-readable Property would be
+// hidden by ordinaryOwnPropertyKeys/checkRead.
+func TestLazyObjectOwnPropertyKeysHidesUnreadableInit(t *testing.T) {
+	sysOwner := &Owner{}
+	attacker := &Owner{}
+	lo := NewLazyObject(sysOwner, nil, map[string]LazyInit{
+		"secret": {
+			Init: func(*LazyObject) (Value, *NativeError) { return Number(1), nil },
+			W:    true, E: true, C: true, R: false,
+		},
+	})
+
+	keys := lo.OwnPropertyKeys(attacker)
+	for _, k := range keys {
+		if k == "secret" {
+			t.Errorf("OwnPropertyKeys(attacker) leaked non-readable pending key %q", k)
+		}
+	}
+	if lo.HasOwnProperty(attacker, "secret") {
+		t.Errorf("HasOwnProperty(attacker, \"secret\") = true, want false")
+	}
+	if lo.HasProperty(attacker, "secret") {
+		t.Errorf("HasProperty(attacker, \"secret\") = true, want false")
+	}
+	// The owner, in contrast, should see it.
+	if !lo.HasOwnProperty(sysOwner, "secret") {
+		t.Errorf("HasOwnProperty(sysOwner, \"secret\") = false, want true")
+	}
+}
+
+// TestLazyObjectDeleteChecksPermission ensures that deleting a
+// pending, non-configurable, system-owned LazyInit is rejected for a
+// caller that isn't its (eventual) owner, rather than silently
+// cancelling the initializer with no check at all.
+func TestLazyObjectDeleteChecksPermission(t *testing.T) {
+	sysOwner := &Owner{}
+	attacker := &Owner{}
+	initRan := false
+	lo := NewLazyObject(sysOwner, nil, map[string]LazyInit{
+		"toString": {
+			Init: func(*LazyObject) (Value, *NativeError) {
+				initRan = true
+				return Number(1), nil
+			},
+			W: true, E: true, C: false, R: true,
+		},
+	})
+
+	if err := lo.Delete(attacker, "toString"); err == nil {
+		t.Errorf("Delete(attacker, \"toString\") succeeded, want a permission/configurability error")
+	}
+	if !initRan {
+		t.Errorf("Delete should have realized the pending initializer in order to check it")
+	}
+	v, err := lo.Get(sysOwner, "toString")
+	if err != nil || v != Number(1) {
+		t.Errorf("Get(\"toString\") after failed delete = %v, %v; want 1, nil (property should survive)", v, err)
+	}
+}
@@ -0,0 +1,280 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+// InternalMethods is a dispatch table of the internal methods defined
+// by ES5.1 §8.6.2 ([[Get]], [[Set]], [[DefineOwnProperty]], etc.).
+// Every object has exactly one InternalMethods table (pointed to by
+// its embedded *object's Methods field), and that table—rather than
+// the concrete Go type of the object—is what determines whether it
+// behaves like a plain object, an Array, a String wrapper, a Proxy,
+// etc.
+//
+// This mirrors the "exotic object" design of ES5.1/ES2015 (and, more
+// directly, the internal-methods-as-a-table approach used by the Boa
+// JS engine): adding a new flavour of exotic object is a matter of
+// writing a new InternalMethods value, not a new Go type hierarchy,
+// and it lets a single object type (object) be shared by everything
+// that doesn't need to override any of these methods.
+//
+// All methods take the affected *object explicitly (rather than
+// being bound methods on it) precisely so that exotic types that
+// embed object, such as Array, can supply their own table while
+// still passing their own *object down to whichever ordinary methods
+// they chose not to override.
+type InternalMethods struct {
+	GetPrototypeOf    func(obj *object) Object
+	SetPrototypeOf    func(obj *object, proto Object) *NativeError
+	IsExtensible      func(obj *object) bool
+	PreventExtensions func(obj *object) *NativeError
+	Get               func(obj *object, caller *Owner, key string) (Value, *NativeError)
+	Set               func(obj *object, caller *Owner, key string, value Value) *NativeError
+	Delete            func(obj *object, caller *Owner, key string) *NativeError
+	HasProperty       func(obj *object, caller *Owner, key string) bool
+	OwnPropertyKeys   func(obj *object, caller *Owner) []string
+	DefineOwnProperty func(obj *object, caller *Owner, key string, desc PropertyDescriptor, throw bool) *NativeError
+}
+
+// OrdinaryInternalMethods is the InternalMethods table used by plain
+// ("ordinary", per the spec's terminology) objects; it is installed
+// by NewObject and is what all of the exotic tables below fall back
+// to for the operations they don't need to intercept.
+var OrdinaryInternalMethods = InternalMethods{
+	GetPrototypeOf:    ordinaryGetPrototypeOf,
+	SetPrototypeOf:    ordinarySetPrototypeOf,
+	IsExtensible:      ordinaryIsExtensible,
+	PreventExtensions: ordinaryPreventExtensions,
+	Get:               ordinaryGet,
+	Set:               ordinarySet,
+	Delete:            ordinaryDelete,
+	HasProperty:       ordinaryHasProperty,
+	OwnPropertyKeys:   ordinaryOwnPropertyKeys,
+	DefineOwnProperty: ordinaryDefineOwnProperty,
+}
+
+func ordinaryGetPrototypeOf(obj *object) Object {
+	return obj.proto
+}
+
+// ordinarySetPrototypeOf implements [[SetPrototypeOf]] per ES2015
+// §9.1.2 (ES5.1 has no equivalent internal method, as it predates
+// Object.setPrototypeOf).
+func ordinarySetPrototypeOf(obj *object, proto Object) *NativeError {
+	if !obj.extensible {
+		return NewNativeError(TypeError, "object is not extensible")
+	}
+	obj.proto = proto
+	return nil
+}
+
+func ordinaryIsExtensible(obj *object) bool {
+	return obj.extensible
+}
+
+func ordinaryPreventExtensions(obj *object) *NativeError {
+	obj.extensible = false
+	return nil
+}
+
+// findProperty walks the prototype chain starting at obj (inclusive),
+// looking for an own property named key, and returns it (plus true)
+// if found on obj or on an ancestor reachable via hasInternalMethods.
+//
+// BUG(cpcallen): this only sees properties stored in some ancestor's
+// properties map, so it will not find a property belonging to a
+// Proxy that appears in the prototype chain (Proxies never populate
+// their own properties map; everything is forwarded to traps/target
+// instead).
+func findProperty(obj *object, key string) (Property, bool) {
+	for o := obj; o != nil; {
+		if pd, ok := o.properties[key]; ok {
+			return pd, true
+		}
+		if o.proto == nil {
+			return Property{}, false
+		}
+		h, ok := o.proto.(hasInternalMethods)
+		if !ok {
+			return Property{}, false
+		}
+		o = h.base()
+	}
+	return Property{}, false
+}
+
+// findInheritedProperty is findProperty restricted to obj's
+// prototype, excluding obj's own properties; it is used by
+// ordinarySet to look for an inherited accessor once an own property
+// has already been ruled out.
+func findInheritedProperty(obj *object, key string) (Property, bool) {
+	if obj.proto == nil {
+		return Property{}, false
+	}
+	h, ok := obj.proto.(hasInternalMethods)
+	if !ok {
+		return Property{}, false
+	}
+	return findProperty(h.base(), key)
+}
+
+// ordinaryGet implements [[Get]] per ES5.1 §8.12.3, subject to the
+// owner-based permission checks described by checkRead.  Per §8.12.3
+// step 6, an accessor's getter is always called with the original
+// receiver (obj.self) as `this`, even when the property it's found
+// via is actually an own property of some ancestor in the prototype
+// chain.
+func ordinaryGet(obj *object, caller *Owner, key string) (Value, *NativeError) {
+	pd, ok := findProperty(obj, key)
+	if !ok {
+		return Undefined{}, nil
+	}
+	if err := checkRead(pd, caller); err != nil {
+		return Undefined{}, err
+	}
+	if pd.Accessor {
+		if pd.Get == nil {
+			return Undefined{}, nil
+		}
+		fn, ok := pd.Get.(Callable)
+		if !ok {
+			return Undefined{}, nil
+		}
+		return fn.Call(obj.self, nil)
+	}
+	return pd.Value, nil
+}
+
+// ordinarySet implements [[Set]] per ES5.1 §8.12.5, subject to the
+// owner-based permission checks described by checkCreate/checkWrite.
+// Per §8.12.5 step 1 (via [[CanPut]]'s use of [[GetProperty]]), a
+// write to a key with no own property but an inherited accessor
+// setter invokes that setter (with obj.self as `this`) rather than
+// shadowing it with a new own data property.
+func ordinarySet(obj *object, caller *Owner, key string, value Value) *NativeError {
+	pd, ok := obj.properties[key]
+	if !ok {
+		if inherited, found := findInheritedProperty(obj, key); found && inherited.Accessor {
+			if inherited.Set == nil {
+				// Read-only accessor: silently ignored (as in
+				// non-strict mode; see the FIXME below).
+				return nil
+			}
+			fn, ok := inherited.Set.(Callable)
+			if !ok {
+				return nil
+			}
+			_, err := fn.Call(obj.self, []Value{value})
+			return err
+		}
+		// Creating new property
+		if err := checkCreate(obj, caller); err != nil {
+			return err
+		}
+		obj.properties[key] = Property{
+			Value: value,
+			Owner: caller,
+			W:     true,
+			E:     true,
+			C:     true,
+			R:     true,
+			I:     false,
+		}
+		return nil
+	}
+	// Updating existing property
+	if err := checkWrite(pd, caller); err != nil {
+		return err
+	}
+	if pd.Accessor {
+		if pd.Set == nil {
+			// FIXME: should be a silent no-op in non-strict mode and
+			// a TypeError in strict mode; for now, just no-op.
+			return nil
+		}
+		fn, ok := pd.Set.(Callable)
+		if !ok {
+			return nil
+		}
+		_, err := fn.Call(obj.self, []Value{value})
+		return err
+	}
+	pd.Value = value
+	obj.properties[key] = pd
+	return nil
+}
+
+// ordinaryDelete implements [[Delete]] per ES5.1 §8.12.7, subject to
+// the owner-based permission checks described by checkDelete.
+func ordinaryDelete(obj *object, caller *Owner, key string) *NativeError {
+	pd, ok := obj.properties[key]
+	if !ok {
+		return nil
+	}
+	if err := checkDelete(pd, caller); err != nil {
+		return err
+	}
+	delete(obj.properties, key)
+	return nil
+}
+
+// ordinaryHasProperty implements [[HasProperty]] per ES5.1 §8.12.6.
+func ordinaryHasProperty(obj *object, caller *Owner, key string) bool {
+	return obj.HasOwnProperty(caller, key) ||
+		obj.proto != nil && obj.proto.HasProperty(caller, key)
+}
+
+// ordinaryOwnPropertyKeys returns the list of (own) property keys as
+// a slice of strings, omitting any caller is not permitted to read.
+func ordinaryOwnPropertyKeys(obj *object, caller *Owner) []string {
+	keys := make([]string, 0, len(obj.properties))
+	for k, pd := range obj.properties {
+		if checkRead(pd, caller) != nil {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// base and methods allow DefineOwnProperty (and any other
+// free-standing function that needs to reach an object's internal
+// methods table given only an Object) to work with any type that
+// embeds object, via Go's automatic promotion of embedded methods:
+// Array, String and Proxy all get these for free just by embedding
+// object, without having to redeclare them.
+func (obj *object) base() *object             { return obj }
+func (obj *object) methods() *InternalMethods { return obj.Methods }
+
+// hasInternalMethods is satisfied by any Object that embeds object
+// (directly or indirectly), which in practice means all of them.
+type hasInternalMethods interface {
+	base() *object
+	methods() *InternalMethods
+}
+
+// DefineOwnProperty implements the [[DefineOwnProperty]] internal
+// method, dispatching to obj's own InternalMethods table so that
+// exotic objects (e.g. Array, with its magic "length") can intercept
+// the operation.  See ordinaryDefineOwnProperty for the algorithm
+// used by plain objects.
+func DefineOwnProperty(obj Object, caller *Owner, key string, desc PropertyDescriptor, throw bool) *NativeError {
+	h, ok := obj.(hasInternalMethods)
+	if !ok {
+		return rejectOrThrow(throw, "object does not support DefineOwnProperty")
+	}
+	return h.methods().DefineOwnProperty(h.base(), caller, key, desc, throw)
+}
@@ -0,0 +1,79 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import "testing"
+
+// TestInheritedGetterReceivesOriginalReceiver covers ES5.1 §8.12.3
+// step 6: a getter inherited from the prototype chain must be called
+// with the object [[Get]] was originally invoked on as `this`, not
+// the prototype object it happens to be defined on.
+func TestInheritedGetterReceivesOriginalReceiver(t *testing.T) {
+	owner := &Owner{}
+	proto := NewObject(owner, nil)
+	child := NewObject(owner, proto)
+
+	var gotThis Value
+	getter := newTestFunc(owner, func(this Value, args []Value) (Value, *NativeError) {
+		gotThis = this
+		return Number(1), nil
+	})
+	if err := DefineOwnProperty(proto, owner, "x", PropertyDescriptor{HasGet: true, Get: getter}, true); err != nil {
+		t.Fatalf("defining getter on proto failed: %v", err)
+	}
+
+	if _, err := child.Get(owner, "x"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if gotThis != Value(child) {
+		t.Errorf("getter's this = %v, want child (%v)", gotThis, child)
+	}
+}
+
+// TestInheritedSetterIsInvoked covers ES5.1 §8.12.5: writing to a
+// property with no own slot but an inherited accessor setter must
+// invoke that setter (with the original object as `this`) rather
+// than silently shadowing it with a new own data property.
+func TestInheritedSetterIsInvoked(t *testing.T) {
+	owner := &Owner{}
+	proto := NewObject(owner, nil)
+	child := NewObject(owner, proto)
+
+	var gotThis Value
+	var gotValue Value
+	setter := newTestFunc(owner, func(this Value, args []Value) (Value, *NativeError) {
+		gotThis = this
+		gotValue = args[0]
+		return Undefined{}, nil
+	})
+	if err := DefineOwnProperty(proto, owner, "x", PropertyDescriptor{HasSet: true, Set: setter}, true); err != nil {
+		t.Fatalf("defining setter on proto failed: %v", err)
+	}
+
+	if err := child.Set(owner, "x", Number(99)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if gotThis != Value(child) {
+		t.Errorf("setter's this = %v, want child (%v)", gotThis, child)
+	}
+	if gotValue != Number(99) {
+		t.Errorf("setter's value argument = %v, want 99", gotValue)
+	}
+	if child.HasOwnProperty(owner, "x") {
+		t.Errorf("child should not have gained an own %q property", "x")
+	}
+}
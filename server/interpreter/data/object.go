@@ -28,55 +28,104 @@ type Object interface {
 	Proto() Object
 
 	// Get returns the current value of the given property or an
-	// NativeError if that was not possible.
-	Get(key string) (Value, *NativeError)
+	// NativeError if that was not possible (e.g. a PermissionError if
+	// caller is not entitled to read it).
+	Get(caller *Owner, key string) (Value, *NativeError)
 
 	// Set sets the given property to the specified value or returns
-	// an NativeError if that was not possible.
-	Set(key string, value Value) *NativeError
+	// an NativeError if that was not possible (e.g. a PermissionError
+	// if caller is not entitled to write it).
+	Set(caller *Owner, key string, value Value) *NativeError
 
 	// Delete attempts to remove the specified property.  If the
-	// property exists but can't be removed for some reason an
-	// NativeError is returned.  (Removing a non-existing property
-	// "succeeds" silently.)
-	Delete(key string) *NativeError
+	// property exists but can't be removed for some reason (not
+	// configurable, or caller lacks permission) an NativeError is
+	// returned.  (Removing a non-existing property "succeeds"
+	// silently.)
+	Delete(caller *Owner, key string) *NativeError
 
 	// OwnPropertyKeys returns the list of (own) property keys as a
-	// slice of strings.
-	OwnPropertyKeys() []string
+	// slice of strings, omitting any caller is not permitted to see.
+	OwnPropertyKeys(caller *Owner) []string
 
 	// HasOwnProperty returns true if the specified property key
-	// exists on the object itself.
-	HasOwnProperty(string) bool
+	// exists on the object itself and caller is permitted to know
+	// that it does.
+	HasOwnProperty(caller *Owner, key string) bool
 
-	// HasProperty returns true if the specified property key
-	// exists on the object or its prototype chain.
-	HasProperty(string) bool
+	// HasProperty returns true if the specified property key exists
+	// on the object or its prototype chain, and caller is permitted
+	// to know that it does.
+	HasProperty(caller *Owner, key string) bool
 }
 
 // object represents typical plain old JavaScript objects with
 // prototype, properties, etc.; this struct is also embedded in other,
-// less-plain object types like Array.
+// less-plain object types like Array, String and Proxy, which
+// override some or all of Methods to get exotic behaviour without
+// requiring a parallel implementation of every Object method.
 type object struct {
 	owner      *Owner
 	proto      Object
 	properties map[string]Property
+	extensible bool
 	f          bool
+
+	// worldWritable, if true, allows any caller (not just owner) to
+	// create new own properties on this object; see checkCreate.
+	worldWritable bool
+
+	// primitiveValue holds the [[PrimitiveValue]] internal slot used
+	// by primitive wrapper objects (String, Number, Boolean); it is
+	// nil for ordinary objects, Arrays, Proxies, etc.
+	primitiveValue Value
+
+	// self is a back-reference to the outermost Object value that
+	// embeds this object (i.e. to itself, for a plain *object; to
+	// the enclosing *Array, *JSString or *Proxy otherwise).  It lets
+	// InternalMethods functions—which only ever receive the embedded
+	// *object—recover any extra state held by an exotic wrapper type.
+	self Object
+
+	// Methods is the dispatch table of internal methods ([[Get]],
+	// [[Set]], etc., per ES5.1 §8.6.2) used to implement this
+	// object's behaviour.  init() does not set it; each constructor
+	// sets it right after calling init(): NewObject installs
+	// &OrdinaryInternalMethods, while exotic constructors such as
+	// NewArray install their own tables instead.  Code that already
+	// holds a *object (rather than just an Object) should generally
+	// prefer calling obj.Methods.Get(obj, caller, key) etc. directly, since
+	// that is what makes exotic behaviour (magic "length", Proxy
+	// traps, ...) take effect; the Object interface methods below
+	// exist for callers that only have an Object and forward to the
+	// same table.
+	Methods *InternalMethods
 }
 
 // Property is a property descriptor, per §8.10 of ES5.1
-//     Value: The actual value of the property.
-//     Owner: Who owns the property (has permission to write it)?
-//     W:     Is the property writeable?
-//     E:     Is the property enumerable?
-//     C:     Is the property configurable?
-//     R:     Is the property world-readable?
-//     I:     Is the property ownership inherited on children?
+//
+//	Value: The actual value of the property, for a data property.
+//	Get:   The getter function, for an accessor property.
+//	Set:   The setter function, for an accessor property.
+//	Accessor: Is this an accessor property (Get/Set) rather than a
+//	          data property (Value)?  Value and Get/Set are
+//	          mutually exclusive: exactly one of them is in use,
+//	          depending on the value of Accessor.
+//	Owner: Who owns the property (has permission to write it)?
+//	W:     Is the property writeable?  (Data properties only;
+//	       meaningless—and always false—for accessor properties,
+//	       which are instead considered writable iff Set != nil.)
+//	E:     Is the property enumerable?
+//	C:     Is the property configurable?
+//	R:     Is the property world-readable?
+//	I:     Is the property ownership inherited on children?
 type Property struct {
-	Value   Value
-	Owner   *Owner
-	W, E, C bool
-	R, I    bool
+	Value    Value
+	Get, Set Value
+	Accessor bool
+	Owner    *Owner
+	W, E, C  bool
+	R, I     bool
 }
 
 // *object must satisfy Object.
@@ -97,115 +146,58 @@ func (object) IsPrimitive() bool {
 	return false
 }
 
-// Proto returns the prototype (parent) object for this object.
-func (obj object) Proto() Object {
-	return obj.proto
+// Proto returns the prototype (parent) object for this object, via
+// the [[GetPrototypeOf]] internal method.
+func (obj *object) Proto() Object {
+	return obj.Methods.GetPrototypeOf(obj)
 }
 
-// Get returns the current value of the given property or an
-// NativeError if that was not possible.
-func (obj object) Get(key string) (Value, *NativeError) {
-	pd, ok := obj.properties[key]
-	// FIXME: permissions check for property readability goes here
-	if ok {
-		return pd.Value, nil
-	}
-	// Try the prototype?
-	proto := obj.Proto()
-	if proto != nil {
-		return proto.Get(key)
-	}
-	return Undefined{}, nil
-}
-
-// Set sets the given property to the specified value or returns an
-// NativeError if that was not possible.
-func (obj *object) Set(key string, value Value) *NativeError {
-	pd, ok := obj.properties[key]
-	if !ok { // Creating new property
-		// FIXME: permissions check for object writability goes here
-		obj.properties[key] = Property{
-			Value: value,
-			Owner: obj.owner, // FIXME: should this be caller?
-			W:     true,
-			E:     true,
-			C:     true,
-			R:     true,
-			I:     false,
-		}
-		return nil
-	}
-	// Updating existing property
-	// FIXME: permissions check for property writeability goes here
-	// FIXME: recurse if necessary
-	pd.Value = value
-	obj.properties[key] = pd
-	return nil
+// Get returns the current value of the given property, via the
+// [[Get]] internal method.
+func (obj *object) Get(caller *Owner, key string) (Value, *NativeError) {
+	return obj.Methods.Get(obj, caller, key)
 }
 
-// Delete removes the specified property if possible.
-//
-// FIXME: perm / immutability checks!
-func (obj *object) Delete(key string) *NativeError {
-	delete(obj.properties, key)
-	return nil
-}
-
-// OwnPropertyKeys returns the list of (own) property keys as a slice
-// of strings.
-func (obj *object) OwnPropertyKeys() []string {
-	keys := make([]string, len(obj.properties))
-	i := 0
-	for k := range obj.properties {
-		keys[i] = k
-		i++
-	}
-	return keys
+// Set sets the given property to the specified value, via the
+// [[Set]] internal method.
+func (obj *object) Set(caller *Owner, key string, value Value) *NativeError {
+	return obj.Methods.Set(obj, caller, key, value)
 }
 
-// HasOwnProperty returns true if the specified property key exists on
-// the object itself.
-func (obj *object) HasOwnProperty(key string) bool {
-	_, exists := obj.properties[key]
-	return exists
+// Delete attempts to remove the specified property, via the
+// [[Delete]] internal method.
+func (obj *object) Delete(caller *Owner, key string) *NativeError {
+	return obj.Methods.Delete(obj, caller, key)
 }
 
-// HasProperty returns true if the specified property key exists on
-// the object or its prototype chain.
-func (obj *object) HasProperty(key string) bool {
-	return obj.HasOwnProperty(key) ||
-		obj.proto != nil && obj.proto.HasProperty(key)
+// OwnPropertyKeys returns the list of (own) property keys, via the
+// [[OwnPropertyKeys]] internal method.
+func (obj *object) OwnPropertyKeys(caller *Owner) []string {
+	return obj.Methods.OwnPropertyKeys(obj, caller)
 }
 
-// ToBoolean always returns true for regular objects.
-func (object) ToBoolean() Boolean {
-	return true
+// HasOwnProperty returns true if the specified property key exists on
+// the object itself and caller is permitted to know that it does.
+func (obj *object) HasOwnProperty(caller *Owner, key string) bool {
+	pd, exists := obj.properties[key]
+	if !exists {
+		return false
+	}
+	return checkRead(pd, caller) == nil
 }
 
-// ToNumber returns the numeric equivalent of the object.
-//
-// BUG(cpcallen): object.ToNumber is not strictly compliant with ES5.1
-// spec; it just returns .ToString().ToNumber().
-func (obj object) ToNumber() Number {
-	return obj.ToString().ToNumber()
+// HasProperty returns true if the specified property key exists,
+// via the [[HasProperty]] internal method.
+func (obj *object) HasProperty(caller *Owner, key string) bool {
+	return obj.Methods.HasProperty(obj, caller, key)
 }
 
-// ToString returns a string representation of the object.  By default
-// this is "[object Object]" for plain objects.
-//
-// BUG(cpcallen): object.ToString should call a user-code toString()
-// method if present.
-func (object) ToString() String {
-	return "[object Object]"
+// ToBoolean always returns true for regular objects.
+func (object) ToBoolean() Boolean {
+	return true
 }
 
-// ToPrimitive defaults to ToNumber on objects.
-//
-// BUG(cpcallen): object.ToPrimitive should prefer to return the
-// result of ToString() on date objects.
-func (obj *object) ToPrimitive() Value {
-	return obj.ToNumber()
-}
+// ToNumber, ToString and ToPrimitive are defined in to_primitive.go.
 
 // NewObject creates a new object with the specified owner and
 // prototype, initialises it as appropriate, and returns a pointer to
@@ -213,15 +205,20 @@ func (obj *object) ToPrimitive() Value {
 func NewObject(owner *Owner, proto Object) *object {
 	var obj = new(object)
 	obj.init(owner, proto)
+	obj.Methods = &OrdinaryInternalMethods
+	obj.self = obj
 	obj.f = true
 	return obj
 }
 
 // init is an internal initialisation routine, called from New and
 // also called when constructing other types of objects such as
-// Arrays, Owners, etc.
+// Arrays, Owners, etc.  It does not set obj.Methods; callers (e.g.
+// NewObject, NewArray) are expected to set it to their own table
+// immediately afterwards.
 func (obj *object) init(owner *Owner, proto Object) {
 	obj.owner = owner
 	obj.proto = proto
 	obj.properties = make(map[string]Property)
+	obj.extensible = true
 }
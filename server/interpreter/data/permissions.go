@@ -0,0 +1,113 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+// PermissionError is the NativeError variant returned when a caller
+// attempts an operation on a property or object it does not own and
+// is not otherwise entitled to perform, per the owner-based
+// permission model described by the R/W/C/I flags on Property (see
+// object.go) and the various check* functions below.
+const PermissionError ErrorType = "PermissionError"
+
+// Root is the sentinel Owner representing system/privileged code.
+// Passing Root as the caller to any of the methods on Object bypasses
+// all permission checks, exactly as though the caller owned every
+// object and property in existence.  It should only be used by the
+// interpreter's own bootstrap code and similar trusted contexts—never
+// derived from (or exposed to) user code.
+var Root = &Owner{}
+
+// checkRead verifies that caller is allowed to read the property
+// described by pd: the property must be world-readable (R) or owned
+// by caller.
+func checkRead(pd Property, caller *Owner) *NativeError {
+	if pd.R || caller == Root || caller == pd.Owner {
+		return nil
+	}
+	return NewNativeError(PermissionError, "permission denied: property is not readable")
+}
+
+// checkWrite verifies that caller is allowed to overwrite the value
+// of an existing property described by pd: the property must be
+// writable (W) or owned by caller.
+func checkWrite(pd Property, caller *Owner) *NativeError {
+	if pd.W || caller == Root || caller == pd.Owner {
+		return nil
+	}
+	return NewNativeError(PermissionError, "permission denied: property is not writable")
+}
+
+// checkCreate verifies that caller is allowed to create a new own
+// property on obj: caller must own obj, obj must be world-writable,
+// or caller must be Root.
+func checkCreate(obj *object, caller *Owner) *NativeError {
+	if obj.worldWritable || caller == Root || caller == obj.owner {
+		return nil
+	}
+	return NewNativeError(PermissionError, "permission denied: object is not writable")
+}
+
+// checkDelete verifies that caller is allowed to delete the property
+// described by pd: the property must be configurable (C) and owned
+// by caller (or caller must be Root).
+func checkDelete(pd Property, caller *Owner) *NativeError {
+	if caller != Root && caller != pd.Owner {
+		return NewNativeError(PermissionError, "permission denied: not owner of property")
+	}
+	if !pd.C {
+		return NewNativeError(TypeError, "property is not configurable")
+	}
+	return nil
+}
+
+// checkReconfigure verifies that caller is allowed to use
+// DefineOwnProperty to alter an existing property described by pd:
+// unlike checkWrite, this is gated purely on ownership (not on W),
+// since DefineOwnProperty can alter a property's attributes (and
+// hence its writability) as well as its value.
+func checkReconfigure(pd Property, caller *Owner) *NativeError {
+	if caller == Root || caller == pd.Owner {
+		return nil
+	}
+	return NewNativeError(PermissionError, "permission denied: not owner of property")
+}
+
+// OwnerFor returns the Owner that should be attributed to a new
+// object created via (e.g., assigned to, or instantiated from) the
+// named property of obj.  Per the I ("ownership inherited") flag on
+// Property, this is normally just caller, but if the property has I
+// set then objects created through it are instead owned by the
+// property's own Owner—e.g., so that methods stored on a shared,
+// world-readable object still construct objects owned by whoever
+// defined the method, not whoever happened to call it.
+func OwnerFor(obj Object, key string, caller *Owner) *Owner {
+	o, ok := obj.(hasInternalMethods)
+	if !ok {
+		return caller
+	}
+	pd, exists := o.base().properties[key]
+	if !exists || !pd.I {
+		return caller
+	}
+	return pd.Owner
+}
+
+// SetWorldWritable marks obj as accepting new own properties from any
+// caller, not just its owner (see checkCreate).
+func (obj *object) SetWorldWritable(w bool) {
+	obj.worldWritable = w
+}
@@ -0,0 +1,119 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import "testing"
+
+func TestCheckRead(t *testing.T) {
+	alice, bob := &Owner{}, &Owner{}
+	tests := []struct {
+		name   string
+		pd     Property
+		caller *Owner
+		ok     bool
+	}{
+		{"owner may always read", Property{Owner: alice}, alice, true},
+		{"world-readable is readable by anyone", Property{Owner: alice, R: true}, bob, true},
+		{"non-owner cannot read non-world-readable", Property{Owner: alice}, bob, false},
+		{"Root bypasses the check", Property{Owner: alice}, Root, true},
+	}
+	for _, tt := range tests {
+		if err := checkRead(tt.pd, tt.caller); (err == nil) != tt.ok {
+			t.Errorf("%s: checkRead error = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckWrite(t *testing.T) {
+	alice, bob := &Owner{}, &Owner{}
+	tests := []struct {
+		name   string
+		pd     Property
+		caller *Owner
+		ok     bool
+	}{
+		{"owner may always write", Property{Owner: alice}, alice, true},
+		{"writable is writable by anyone", Property{Owner: alice, W: true}, bob, true},
+		{"non-owner cannot write non-writable", Property{Owner: alice}, bob, false},
+		{"Root bypasses the check", Property{Owner: alice}, Root, true},
+	}
+	for _, tt := range tests {
+		if err := checkWrite(tt.pd, tt.caller); (err == nil) != tt.ok {
+			t.Errorf("%s: checkWrite error = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckCreate(t *testing.T) {
+	alice, bob := &Owner{}, &Owner{}
+	tests := []struct {
+		name   string
+		obj    *object
+		caller *Owner
+		ok     bool
+	}{
+		{"owner may always create", &object{owner: alice}, alice, true},
+		{"world-writable object accepts any caller", &object{owner: alice, worldWritable: true}, bob, true},
+		{"non-owner cannot create on non-world-writable object", &object{owner: alice}, bob, false},
+		{"Root bypasses the check", &object{owner: alice}, Root, true},
+	}
+	for _, tt := range tests {
+		if err := checkCreate(tt.obj, tt.caller); (err == nil) != tt.ok {
+			t.Errorf("%s: checkCreate error = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckDelete(t *testing.T) {
+	alice, bob := &Owner{}, &Owner{}
+	tests := []struct {
+		name   string
+		pd     Property
+		caller *Owner
+		ok     bool
+	}{
+		{"owner may delete configurable property", Property{Owner: alice, C: true}, alice, true},
+		{"owner cannot delete non-configurable property", Property{Owner: alice, C: false}, alice, false},
+		{"non-owner cannot delete even if configurable", Property{Owner: alice, C: true}, bob, false},
+		{"Root bypasses ownership but not configurability", Property{Owner: alice, C: false}, Root, false},
+		{"Root may delete a configurable property", Property{Owner: alice, C: true}, Root, true},
+	}
+	for _, tt := range tests {
+		if err := checkDelete(tt.pd, tt.caller); (err == nil) != tt.ok {
+			t.Errorf("%s: checkDelete error = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckReconfigure(t *testing.T) {
+	alice, bob := &Owner{}, &Owner{}
+	tests := []struct {
+		name   string
+		pd     Property
+		caller *Owner
+		ok     bool
+	}{
+		{"owner may reconfigure even if not writable", Property{Owner: alice, W: false}, alice, true},
+		{"non-owner may not reconfigure even if writable", Property{Owner: alice, W: true}, bob, false},
+		{"Root bypasses the check", Property{Owner: alice}, Root, true},
+	}
+	for _, tt := range tests {
+		if err := checkReconfigure(tt.pd, tt.caller); (err == nil) != tt.ok {
+			t.Errorf("%s: checkReconfigure error = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+// PropertyDescriptor represents the (possibly partial) property
+// descriptor passed to Object.defineProperty and similar; it is the
+// Go analogue of the Property Descriptor specification type defined
+// in §8.10 of ES5.1.
+//
+// Unlike Property (which always describes a complete, concrete
+// property of some object) a PropertyDescriptor may have any subset
+// of its fields present; the HasXXX fields record which fields of
+// the descriptor were actually specified, with an absent field
+// meaning "leave this attribute unchanged" rather than "unset this
+// attribute".
+type PropertyDescriptor struct {
+	Value           Value
+	Get, Set        Value
+	Writable        bool
+	Enumerable      bool
+	Configurable    bool
+	HasValue        bool
+	HasGet          bool
+	HasSet          bool
+	HasWritable     bool
+	HasEnumerable   bool
+	HasConfigurable bool
+}
+
+// IsDataDescriptor returns true if desc specifies a Value and/or
+// Writable attribute, per §8.10.2 of ES5.1.
+func IsDataDescriptor(desc PropertyDescriptor) bool {
+	return desc.HasValue || desc.HasWritable
+}
+
+// IsAccessorDescriptor returns true if desc specifies a Get and/or
+// Set attribute, per §8.10.1 of ES5.1.
+func IsAccessorDescriptor(desc PropertyDescriptor) bool {
+	return desc.HasGet || desc.HasSet
+}
+
+// IsGenericDescriptor returns true if desc specifies neither data nor
+// accessor attributes (e.g. {enumerable: true} on its own), per
+// §8.10.3 of ES5.1.
+func IsGenericDescriptor(desc PropertyDescriptor) bool {
+	return !IsDataDescriptor(desc) && !IsAccessorDescriptor(desc)
+}
+
+// ordinaryDefineOwnProperty implements the [[DefineOwnProperty]]
+// internal method for ordinary objects, per §8.12.9 of ES5.1, subject
+// also to the owner-based permission checks described by
+// checkCreate/checkReconfigure.  It creates or updates the named own
+// property of obj according to desc, enforcing the invariants of
+// non-configurable properties along the way.  If the requested
+// change is invalid, and throw is true, a TypeError NativeError is
+// returned; if throw is false the function instead returns nil
+// having made no changes, per the "Reject" steps of the algorithm (so
+// a non-throwing reject is indistinguishable from success to the
+// caller).
+func ordinaryDefineOwnProperty(obj *object, caller *Owner, key string, desc PropertyDescriptor, throw bool) *NativeError {
+	current, exists := obj.properties[key]
+
+	// Step 1/3: no current property; create one from the defaults,
+	// provided the object is extensible.
+	if !exists {
+		if !obj.extensible {
+			return rejectOrThrow(throw, "object is not extensible")
+		}
+		if err := checkCreate(obj, caller); err != nil {
+			return err
+		}
+		obj.properties[key] = fromDescriptor(desc, Property{Owner: caller})
+		return nil
+	}
+
+	if err := checkReconfigure(current, caller); err != nil {
+		return err
+	}
+
+	// Steps 5/6: trivial case—every field of desc (if present) already
+	// matches current, so there is nothing to do.
+	if isNoOp(current, desc) {
+		return nil
+	}
+
+	// Step 7: reject any attempt to change anything on a
+	// non-configurable property other than a writable -> non-writable
+	// data-property transition, or a value change permitted by step
+	// 10.a.ii below.
+	if !current.C {
+		if desc.HasConfigurable && desc.Configurable {
+			return rejectOrThrow(throw, "cannot redefine non-configurable property "+key)
+		}
+		if desc.HasEnumerable && desc.Enumerable != current.E {
+			return rejectOrThrow(throw, "cannot change enumerability of non-configurable property "+key)
+		}
+	}
+
+	switch {
+	case IsGenericDescriptor(desc):
+		// Step 8: no further validation needed.
+	case current.Accessor != IsAccessorDescriptor(desc) && (IsDataDescriptor(desc) || IsAccessorDescriptor(desc)):
+		// Step 9: converting between data and accessor.
+		if !current.C {
+			return rejectOrThrow(throw, "cannot convert non-configurable property "+key)
+		}
+	case !current.Accessor:
+		// Step 10: both current and desc (if it specifies anything
+		// relevant) describe data properties.
+		if !current.C && !current.W {
+			if desc.HasWritable && desc.Writable {
+				return rejectOrThrow(throw, "cannot make non-configurable property "+key+" writable")
+			}
+			if desc.HasValue && !sameValue(desc.Value, current.Value) {
+				return rejectOrThrow(throw, "cannot change value of non-configurable, non-writable property "+key)
+			}
+		}
+	default:
+		// Step 11: both current and desc (if it specifies anything
+		// relevant) describe accessor properties.
+		if !current.C {
+			if desc.HasSet && desc.Set != current.Set {
+				return rejectOrThrow(throw, "cannot change setter of non-configurable property "+key)
+			}
+			if desc.HasGet && desc.Get != current.Get {
+				return rejectOrThrow(throw, "cannot change getter of non-configurable property "+key)
+			}
+		}
+	}
+
+	// Step 12: apply the (validated) changes.
+	obj.properties[key] = fromDescriptor(desc, current)
+	return nil
+}
+
+// fromDescriptor merges desc on top of base (an existing Property, or
+// the zero value when there is no pre-existing property) per the
+// defaulting rules of §8.12.9 step 3/4 and 12, switching
+// representation between data and accessor as dictated by desc.
+func fromDescriptor(desc PropertyDescriptor, base Property) Property {
+	p := base
+	p.E = orBool(desc.HasEnumerable, desc.Enumerable, base.E)
+	p.C = orBool(desc.HasConfigurable, desc.Configurable, base.C)
+
+	if IsAccessorDescriptor(desc) {
+		p.Accessor = true
+		p.Value = nil
+		p.W = false
+		if desc.HasGet {
+			p.Get = desc.Get
+		}
+		if desc.HasSet {
+			p.Set = desc.Set
+		}
+	} else if IsDataDescriptor(desc) || !base.Accessor {
+		p.Accessor = false
+		p.Get, p.Set = nil, nil
+		p.W = orBool(desc.HasWritable, desc.Writable, base.W)
+		if desc.HasValue {
+			p.Value = desc.Value
+		} else if p.Value == nil {
+			p.Value = Undefined{}
+		}
+	}
+	return p
+}
+
+// isNoOp returns true if desc describes no change at all relative to
+// current, per §8.12.9 steps 5 and 6.
+func isNoOp(current Property, desc PropertyDescriptor) bool {
+	if desc.HasConfigurable && desc.Configurable != current.C {
+		return false
+	}
+	if desc.HasEnumerable && desc.Enumerable != current.E {
+		return false
+	}
+	if IsGenericDescriptor(desc) {
+		return true
+	}
+	if current.Accessor {
+		if !IsAccessorDescriptor(desc) {
+			return false
+		}
+		return (!desc.HasGet || desc.Get == current.Get) &&
+			(!desc.HasSet || desc.Set == current.Set)
+	}
+	if IsAccessorDescriptor(desc) {
+		return false
+	}
+	return (!desc.HasWritable || desc.Writable == current.W) &&
+		(!desc.HasValue || sameValue(desc.Value, current.Value))
+}
+
+// orBool returns newVal if present is true, else old.
+func orBool(present bool, newVal bool, old bool) bool {
+	if present {
+		return newVal
+	}
+	return old
+}
+
+// sameValue reports whether two property values should be considered
+// identical for the purposes of §8.12.9; it is distinct from the
+// language-level === operator, but in the absence of a SameValue
+// algorithm here we fall back to simple interface equality.
+//
+// FIXME: this should implement the actual SameValue algorithm
+// (treating NaN as equal to itself and +0 as distinct from -0).
+func sameValue(a, b Value) bool {
+	return a == b
+}
+
+// rejectOrThrow implements the "Reject" abstract operation used
+// throughout §8.12.9: if throw is true it returns a TypeError;
+// otherwise it returns nil, signalling that the operation silently
+// did nothing.
+func rejectOrThrow(throw bool, msg string) *NativeError {
+	if !throw {
+		return nil
+	}
+	return NewNativeError(TypeError, msg)
+}
@@ -0,0 +1,98 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import "testing"
+
+func TestDefineOwnPropertyCreate(t *testing.T) {
+	owner := &Owner{}
+	obj := NewObject(owner, nil)
+	if err := DefineOwnProperty(obj, owner, "x", PropertyDescriptor{
+		HasValue: true, Value: Number(42),
+	}, true); err != nil {
+		t.Fatalf("defining a new property failed: %v", err)
+	}
+	v, err := obj.Get(owner, "x")
+	if err != nil || v != Number(42) {
+		t.Errorf("Get(\"x\") = %v, %v; want 42, nil", v, err)
+	}
+}
+
+func TestDefineOwnPropertyRejectsReconfigureOfNonConfigurable(t *testing.T) {
+	owner := &Owner{}
+	obj := NewObject(owner, nil)
+	desc := PropertyDescriptor{
+		HasValue: true, Value: Number(1),
+		HasConfigurable: true, Configurable: false,
+	}
+	if err := DefineOwnProperty(obj, owner, "x", desc, true); err != nil {
+		t.Fatalf("initial define failed: %v", err)
+	}
+	// Attempting to make it configurable again must be rejected.
+	err := DefineOwnProperty(obj, owner, "x", PropertyDescriptor{
+		HasConfigurable: true, Configurable: true,
+	}, true)
+	if err == nil {
+		t.Errorf("redefining non-configurable property as configurable should have failed")
+	}
+	// A non-throwing redefinition should fail silently (nil error) and
+	// leave the property untouched.
+	if err := DefineOwnProperty(obj, owner, "x", PropertyDescriptor{
+		HasValue: true, Value: Number(2),
+	}, false); err != nil {
+		t.Errorf("non-throwing reject should return nil, got %v", err)
+	}
+	v, _ := obj.Get(owner, "x")
+	if v != Number(1) {
+		t.Errorf("value should be unchanged by rejected define, got %v", v)
+	}
+}
+
+func TestDefineOwnPropertyDataToAccessor(t *testing.T) {
+	owner := &Owner{}
+	obj := NewObject(owner, nil)
+	if err := DefineOwnProperty(obj, owner, "x", PropertyDescriptor{
+		HasValue: true, Value: Number(1),
+		HasConfigurable: true, Configurable: true,
+	}, true); err != nil {
+		t.Fatalf("initial define failed: %v", err)
+	}
+	getter := newTestFunc(owner, func(this Value, args []Value) (Value, *NativeError) {
+		return Number(99), nil
+	})
+	if err := DefineOwnProperty(obj, owner, "x", PropertyDescriptor{
+		HasGet: true, Get: getter,
+	}, true); err != nil {
+		t.Fatalf("converting to accessor failed: %v", err)
+	}
+	v, err := obj.Get(owner, "x")
+	if err != nil || v != Number(99) {
+		t.Errorf("Get(\"x\") after conversion = %v, %v; want 99, nil", v, err)
+	}
+}
+
+func TestDefineOwnPropertyRejectsOnNonExtensibleObject(t *testing.T) {
+	owner := &Owner{}
+	obj := NewObject(owner, nil)
+	obj.extensible = false
+	err := DefineOwnProperty(obj, owner, "x", PropertyDescriptor{
+		HasValue: true, Value: Number(1),
+	}, true)
+	if err == nil {
+		t.Errorf("defining a new property on a non-extensible object should have failed")
+	}
+}
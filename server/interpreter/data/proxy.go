@@ -0,0 +1,207 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import "strconv"
+
+// Proxy is an exotic object implementing (a subset of) ES2015's
+// Proxy: every internal method it supports is forwarded to the
+// like-named trap on handler, if present, and falls back to
+// performing the corresponding operation on target otherwise.
+//
+// Only the "get", "set", "has" and "deleteProperty" and "ownKeys"
+// traps are currently supported; the others (getPrototypeOf,
+// defineProperty, etc.) just forward unconditionally to target.
+type Proxy struct {
+	object
+	target  Object
+	handler Object
+}
+
+// *Proxy must satisfy Object.
+var _ Object = (*Proxy)(nil)
+
+// NewProxy creates a new Proxy wrapping target, with traps looked up
+// on handler.  A Proxy has no meaningful prototype of its own (it is
+// forwarded to target).  owner is recorded on the Proxy object, but
+// no operation on the Proxy consults it: every operation goes to a
+// trap or to target.
+func NewProxy(owner *Owner, target Object, handler Object) *Proxy {
+	p := new(Proxy)
+	p.init(owner, nil)
+	p.Methods = &ProxyInternalMethods
+	p.self = p
+	p.target = target
+	p.handler = handler
+	return p
+}
+
+// ProxyInternalMethods is the InternalMethods table used by Proxy
+// objects.
+var ProxyInternalMethods = InternalMethods{
+	GetPrototypeOf:    proxyGetPrototypeOf,
+	SetPrototypeOf:    proxySetPrototypeOf,
+	IsExtensible:      proxyIsExtensible,
+	PreventExtensions: proxyPreventExtensions,
+	Get:               proxyGet,
+	Set:               proxySet,
+	Delete:            proxyDelete,
+	HasProperty:       proxyHasProperty,
+	OwnPropertyKeys:   proxyOwnPropertyKeys,
+	DefineOwnProperty: proxyDefineOwnProperty,
+}
+
+// asProxy recovers the *Proxy for which obj is the embedded object,
+// via obj.self (see the comment on that field).
+func (obj *object) asProxy() *Proxy {
+	return obj.self.(*Proxy)
+}
+
+// trap looks up the named trap function on the proxy's handler, and
+// returns it (plus true) if present and callable.  The lookup itself
+// is done as Root (rather than whichever caller triggered the
+// operation being trapped), since which traps a handler provides is
+// an implementation detail of the Proxy, not something subject to the
+// permission model of whatever code is using it.
+func (p *Proxy) trap(name string) (Callable, bool) {
+	if p.handler == nil {
+		return nil, false
+	}
+	v, err := p.handler.Get(Root, name)
+	if err != nil {
+		return nil, false
+	}
+	fn, ok := v.(Callable)
+	return fn, ok
+}
+
+// HasOwnProperty overrides (object).HasOwnProperty, forwarding to
+// target since there is no "getOwnPropertyDescriptor" trap among
+// those this Proxy supports (see the type comment); this keeps it
+// consistent with HasProperty and OwnPropertyKeys, which also fall
+// back to target in the absence of a more specific trap.
+func (p *Proxy) HasOwnProperty(caller *Owner, key string) bool {
+	return p.target.HasOwnProperty(caller, key)
+}
+
+func proxyGetPrototypeOf(obj *object) Object {
+	return obj.asProxy().target.Proto()
+}
+
+func proxySetPrototypeOf(obj *object, proto Object) *NativeError {
+	target := obj.asProxy().target
+	h, ok := target.(hasInternalMethods)
+	if !ok {
+		return NewNativeError(TypeError, "proxy target does not support setPrototypeOf")
+	}
+	return h.methods().SetPrototypeOf(h.base(), proto)
+}
+
+func proxyIsExtensible(obj *object) bool {
+	target := obj.asProxy().target
+	if h, ok := target.(hasInternalMethods); ok {
+		return h.methods().IsExtensible(h.base())
+	}
+	return true
+}
+
+func proxyPreventExtensions(obj *object) *NativeError {
+	target := obj.asProxy().target
+	h, ok := target.(hasInternalMethods)
+	if !ok {
+		return NewNativeError(TypeError, "proxy target does not support preventExtensions")
+	}
+	return h.methods().PreventExtensions(h.base())
+}
+
+func proxyGet(obj *object, caller *Owner, key string) (Value, *NativeError) {
+	p := obj.asProxy()
+	if fn, ok := p.trap("get"); ok {
+		return fn.Call(p.handler, []Value{p.target, String(key)})
+	}
+	return p.target.Get(caller, key)
+}
+
+func proxySet(obj *object, caller *Owner, key string, value Value) *NativeError {
+	p := obj.asProxy()
+	if fn, ok := p.trap("set"); ok {
+		_, err := fn.Call(p.handler, []Value{p.target, String(key), value})
+		return err
+	}
+	return p.target.Set(caller, key, value)
+}
+
+func proxyDelete(obj *object, caller *Owner, key string) *NativeError {
+	p := obj.asProxy()
+	if fn, ok := p.trap("deleteProperty"); ok {
+		_, err := fn.Call(p.handler, []Value{p.target, String(key)})
+		return err
+	}
+	return p.target.Delete(caller, key)
+}
+
+func proxyHasProperty(obj *object, caller *Owner, key string) bool {
+	p := obj.asProxy()
+	if fn, ok := p.trap("has"); ok {
+		v, err := fn.Call(p.handler, []Value{p.target, String(key)})
+		if err != nil {
+			return false
+		}
+		return bool(v.ToBoolean())
+	}
+	return p.target.HasProperty(caller, key)
+}
+
+func proxyOwnPropertyKeys(obj *object, caller *Owner) []string {
+	p := obj.asProxy()
+	if fn, ok := p.trap("ownKeys"); ok {
+		v, err := fn.Call(p.handler, []Value{p.target})
+		if err == nil {
+			if result, ok := v.(Object); ok {
+				return arrayLikeToStrings(result, caller)
+			}
+		}
+	}
+	return p.target.OwnPropertyKeys(caller)
+}
+
+// arrayLikeToStrings reads obj as an array-like value—per the spec's
+// CreateListFromArrayLike—and returns its elements, read via Get and
+// coerced with ToString.  This is how the result of the "ownKeys"
+// trap (which is a list of key *values*, not an object whose own
+// property keys happen to be array indices) must be interpreted.
+func arrayLikeToStrings(obj Object, caller *Owner) []string {
+	length, err := obj.Get(caller, "length")
+	if err != nil {
+		return nil
+	}
+	n := int(length.ToNumber())
+	keys := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := obj.Get(caller, strconv.Itoa(i))
+		if err != nil {
+			return keys
+		}
+		keys = append(keys, string(v.ToString()))
+	}
+	return keys
+}
+
+func proxyDefineOwnProperty(obj *object, caller *Owner, key string, desc PropertyDescriptor, throw bool) *NativeError {
+	p := obj.asProxy()
+	return DefineOwnProperty(p.target, caller, key, desc, throw)
+}
@@ -0,0 +1,94 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import "testing"
+
+func TestProxyGetFallsBackToTarget(t *testing.T) {
+	owner := &Owner{}
+	target := NewObject(owner, nil)
+	if err := target.Set(owner, "x", Number(42)); err != nil {
+		t.Fatalf("setting up target failed: %v", err)
+	}
+	p := NewProxy(owner, target, nil)
+	v, err := p.Get(owner, "x")
+	if err != nil || v != Number(42) {
+		t.Errorf("Get(\"x\") = %v, %v; want 42, nil", v, err)
+	}
+}
+
+func TestProxyGetUsesTrap(t *testing.T) {
+	owner := &Owner{}
+	target := NewObject(owner, nil)
+	handler := NewObject(owner, nil)
+	trap := newTestFunc(owner, func(this Value, args []Value) (Value, *NativeError) {
+		return Number(7), nil
+	})
+	if err := handler.Set(owner, "get", trap); err != nil {
+		t.Fatalf("installing trap failed: %v", err)
+	}
+	p := NewProxy(owner, target, handler)
+	v, err := p.Get(owner, "x")
+	if err != nil || v != Number(7) {
+		t.Errorf("Get(\"x\") = %v, %v; want 7, nil", v, err)
+	}
+}
+
+// TestProxyOwnKeysTrapReadsArrayLikeElements covers the ownKeys trap
+// contract: its return value is an array-like whose *elements* (read
+// by index, per CreateListFromArrayLike) are the reported keys, not
+// an object whose own property keys happen to look like array
+// indices.
+func TestProxyOwnKeysTrapReadsArrayLikeElements(t *testing.T) {
+	owner := &Owner{}
+	target := NewObject(owner, nil)
+	handler := NewObject(owner, nil)
+	result := NewArray(owner, nil)
+	if err := result.Set(owner, "0", String("foo")); err != nil {
+		t.Fatalf("building trap result failed: %v", err)
+	}
+	if err := result.Set(owner, "1", String("bar")); err != nil {
+		t.Fatalf("building trap result failed: %v", err)
+	}
+	trap := newTestFunc(owner, func(this Value, args []Value) (Value, *NativeError) {
+		return result, nil
+	})
+	if err := handler.Set(owner, "ownKeys", trap); err != nil {
+		t.Fatalf("installing trap failed: %v", err)
+	}
+	p := NewProxy(owner, target, handler)
+
+	keys := p.OwnPropertyKeys(owner)
+	if len(keys) != 2 || keys[0] != "foo" || keys[1] != "bar" {
+		t.Errorf("OwnPropertyKeys() = %v, want [foo bar]", keys)
+	}
+}
+
+func TestProxyHasPropertyFallsBackToTarget(t *testing.T) {
+	owner := &Owner{}
+	target := NewObject(owner, nil)
+	if err := target.Set(owner, "x", Number(1)); err != nil {
+		t.Fatalf("setting up target failed: %v", err)
+	}
+	p := NewProxy(owner, target, nil)
+	if !p.HasProperty(owner, "x") {
+		t.Errorf("HasProperty(\"x\") = false, want true")
+	}
+	if p.HasProperty(owner, "y") {
+		t.Errorf("HasProperty(\"y\") = true, want false")
+	}
+}
@@ -0,0 +1,147 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import "strconv"
+
+// JSString is an exotic object implementing a String wrapper object
+// (i.e., the object created by `new String("foo")`, or coerced to
+// internally when a method is called on a string primitive).  Per
+// ES5.1 §15.5.5.2 it exposes each UTF-16 code unit of its wrapped
+// value—held in the embedded object's primitiveValue slot—as a
+// read-only, non-configurable, enumerable indexed property, plus a
+// read-only "length".
+type JSString struct {
+	object
+}
+
+// *JSString must satisfy Object.
+var _ Object = (*JSString)(nil)
+
+// NewString creates a new String wrapper object for value, with the
+// given owner and prototype (usually String.prototype).
+func NewString(owner *Owner, proto Object, value String) *JSString {
+	s := new(JSString)
+	s.init(owner, proto)
+	s.Methods = &StringInternalMethods
+	s.self = s
+	s.primitiveValue = value
+	s.properties["length"] = Property{
+		Value: Number(len(stringChars(&s.object))),
+		Owner: owner,
+		W:     false,
+		E:     false,
+		C:     false,
+		R:     true,
+	}
+	return s
+}
+
+// StringInternalMethods is the InternalMethods table used by String
+// wrapper objects; indexed character access is synthesised from the
+// wrapped value rather than stored as ordinary properties, and
+// "length" is an ordinary property fixed by NewString; neither may be
+// overwritten, deleted or redefined.
+var StringInternalMethods = InternalMethods{
+	GetPrototypeOf:    ordinaryGetPrototypeOf,
+	SetPrototypeOf:    ordinarySetPrototypeOf,
+	IsExtensible:      ordinaryIsExtensible,
+	PreventExtensions: ordinaryPreventExtensions,
+	Get:               stringGet,
+	Set:               stringSet,
+	Delete:            stringDelete,
+	HasProperty:       stringHasProperty,
+	OwnPropertyKeys:   stringOwnPropertyKeys,
+	DefineOwnProperty: stringDefineOwnProperty,
+}
+
+// stringChars returns the UTF-16-ish code units of obj's wrapped
+// string value.
+//
+// BUG(cpcallen): this uses Go runes, not UTF-16 code units, so it
+// will misbehave for strings containing characters outside the BMP.
+func stringChars(obj *object) []rune {
+	return []rune(string(obj.primitiveValue.(String)))
+}
+
+// HasOwnProperty overrides (object).HasOwnProperty so that the
+// synthesized indexed-character properties are reported as present,
+// consistent with stringGet, stringHasProperty and
+// stringOwnPropertyKeys.
+func (s *JSString) HasOwnProperty(caller *Owner, key string) bool {
+	if idx, ok := arrayIndex(key); ok && int(idx) < len(stringChars(&s.object)) {
+		return true
+	}
+	return s.object.HasOwnProperty(caller, key)
+}
+
+func stringGet(obj *object, caller *Owner, key string) (Value, *NativeError) {
+	if idx, ok := arrayIndex(key); ok {
+		chars := stringChars(obj)
+		if int(idx) < len(chars) {
+			return String(chars[idx]), nil
+		}
+	}
+	return ordinaryGet(obj, caller, key)
+}
+
+func stringSet(obj *object, caller *Owner, key string, value Value) *NativeError {
+	if key == "length" {
+		return nil // silently ignored: length is read-only
+	}
+	if idx, ok := arrayIndex(key); ok && int(idx) < len(stringChars(obj)) {
+		return nil // silently ignored: indexed chars are read-only
+	}
+	return ordinarySet(obj, caller, key, value)
+}
+
+func stringDelete(obj *object, caller *Owner, key string) *NativeError {
+	if key == "length" {
+		return NewNativeError(TypeError, "cannot delete non-configurable property length")
+	}
+	if idx, ok := arrayIndex(key); ok && int(idx) < len(stringChars(obj)) {
+		return NewNativeError(TypeError, "cannot delete non-configurable property "+key)
+	}
+	return ordinaryDelete(obj, caller, key)
+}
+
+func stringHasProperty(obj *object, caller *Owner, key string) bool {
+	if idx, ok := arrayIndex(key); ok && int(idx) < len(stringChars(obj)) {
+		return true
+	}
+	return ordinaryHasProperty(obj, caller, key)
+}
+
+func stringOwnPropertyKeys(obj *object, caller *Owner) []string {
+	chars := stringChars(obj)
+	keys := make([]string, 0, len(chars)+len(obj.properties))
+	for i := range chars {
+		keys = append(keys, strconv.Itoa(i))
+	}
+	keys = append(keys, ordinaryOwnPropertyKeys(obj, caller)...)
+	return keys
+}
+
+func stringDefineOwnProperty(obj *object, caller *Owner, key string, desc PropertyDescriptor, throw bool) *NativeError {
+	if key == "length" {
+		return rejectOrThrow(throw, "cannot redefine non-configurable property length")
+	}
+	if idx, ok := arrayIndex(key); ok && int(idx) < len(stringChars(obj)) {
+		return rejectOrThrow(throw, "cannot redefine non-configurable property "+key)
+	}
+	return ordinaryDefineOwnProperty(obj, caller, key, desc, throw)
+}
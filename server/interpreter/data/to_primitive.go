@@ -0,0 +1,131 @@
+/* Copyright 2017 Google Inc.
+ * https://github.com/NeilFraser/CodeCity
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import "math"
+
+// Hint indicates which of valueOf/toString [[DefaultValue]] (ES5.1
+// §8.12.8) should try first when converting an object to a
+// primitive value.  HintDefault defers to the object's own preferred
+// hint (Number for everything except Date, which prefers String; see
+// hasDefaultHint below).
+type Hint int
+
+// The three hints defined by ES5.1 §9.1/§8.12.8.
+const (
+	HintDefault Hint = iota
+	HintNumber
+	HintString
+)
+
+// Callable is satisfied by any function value the interpreter can
+// invoke on Object's behalf—e.g. a user-code valueOf or toString
+// method reached via Get, or a getter/setter on an accessor
+// property.  The interpreter's function-value type is expected to
+// satisfy this trivially; it exists here, in data, purely so that
+// data can call into user code without importing the interpreter
+// package (which imports data).
+type Callable interface {
+	Call(this Value, args []Value) (Value, *NativeError)
+}
+
+// hasDefaultHint is implemented by exotic objects (namely Date) that
+// want HintDefault to resolve to something other than HintNumber.
+type hasDefaultHint interface {
+	defaultHint() Hint
+}
+
+// ToPrimitive implements the [[DefaultValue]] internal method per
+// ES5.1 §8.12.8/§9.1: it looks up "valueOf" and "toString" via obj's
+// normal Get path (so inherited methods are found), in the order
+// dictated by hint, and calls whichever of them are callable; the
+// first one to return a primitive value wins.  If neither does, it
+// returns a TypeError.
+//
+// caller is passed through to Get, so that looking up valueOf/
+// toString is itself subject to the usual permission checks; pass
+// Root to bypass them (e.g. for implicit coercions with no natural
+// caller, such as those performed by (*object).ToString()).
+func ToPrimitive(obj Object, hint Hint, caller *Owner) (Value, *NativeError) {
+	if hint == HintDefault {
+		hint = HintNumber
+		if d, ok := obj.(hasDefaultHint); ok {
+			hint = d.defaultHint()
+		}
+	}
+	methodNames := [2]string{"valueOf", "toString"}
+	if hint == HintString {
+		methodNames = [2]string{"toString", "valueOf"}
+	}
+	for _, name := range methodNames {
+		v, err := obj.Get(caller, name)
+		if err != nil {
+			return nil, err
+		}
+		fn, ok := v.(Callable)
+		if !ok {
+			continue
+		}
+		result, err := fn.Call(obj, nil)
+		if err != nil {
+			return nil, err
+		}
+		if result.IsPrimitive() {
+			return result, nil
+		}
+	}
+	return nil, NewNativeError(TypeError, "cannot convert object to primitive value")
+}
+
+// ToPrimitive implements the Value interface for plain objects by
+// calling the package-level ToPrimitive with the default hint and
+// Root as caller, since (being constrained by the signature required
+// by Value) it has no way to accept either from its own caller.
+// Prefer calling the package-level ToPrimitive directly—e.g. from
+// within the interpreter, which has both a real caller and a way to
+// report an error—whenever possible.
+func (obj *object) ToPrimitive() Value {
+	v, err := ToPrimitive(obj, HintDefault, Root)
+	if err != nil {
+		return obj
+	}
+	return v
+}
+
+// ToString returns a string representation of the object, calling a
+// user-code toString() or valueOf() method if present (per
+// ToPrimitive(HintString)), and falling back to "[object Object]"
+// if neither yields a primitive.
+func (obj *object) ToString() String {
+	v, err := ToPrimitive(obj, HintString, Root)
+	if err != nil {
+		return "[object Object]"
+	}
+	return v.ToString()
+}
+
+// ToNumber returns the numeric equivalent of the object, calling a
+// user-code valueOf() or toString() method if present (per
+// ToPrimitive(HintNumber)), and falling back to NaN if neither
+// yields a primitive.
+func (obj *object) ToNumber() Number {
+	v, err := ToPrimitive(obj, HintNumber, Root)
+	if err != nil {
+		return Number(math.NaN())
+	}
+	return v.ToNumber()
+}